@@ -0,0 +1,206 @@
+package pid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// TuningRule selects which Ziegler-Nichols style table the Autotuner uses to
+// convert the measured ultimate gain and period into PID gains.
+type TuningRule int
+
+const (
+	// TuningRuleClassic is the original Ziegler-Nichols "classic PID" table.
+	TuningRuleClassic TuningRule = iota
+	// TuningRuleLessOvershoot trades some responsiveness for reduced overshoot.
+	TuningRuleLessOvershoot
+	// TuningRuleNoOvershoot is the most conservative table, aimed at
+	// eliminating overshoot entirely at the cost of a slower response.
+	TuningRuleNoOvershoot
+)
+
+// Autotuner estimates Kp, Ki, and Kd using the Åström–Hägglund relay-feedback
+// method. It drives the plant with a relay (bang-bang) output around
+// InitialOutput, waits for the resulting limit cycle to settle, and derives
+// the ultimate gain Ku and ultimate period Pu from the oscillation.
+type Autotuner struct {
+	Setpoint       float64       // target value to oscillate around
+	Step           float64       // relay output step size (+/- around InitialOutput)
+	NoiseBand      float64       // hysteresis band used to reject measurement noise
+	InitialOutput  float64       // output level the relay steps are applied around
+	Rule           TuningRule    // Ziegler-Nichols table used to derive gains
+	SampleInterval time.Duration // how often Measure is polled; defaults to 10ms
+	StableCycles   int           // consecutive cycles that must agree within Tolerance
+	Tolerance      float64       // fractional tolerance between consecutive cycles
+
+	// Measure returns the current measured process value.
+	Measure func() float64
+	// Output drives the plant with the relay's control output.
+	Output func(float64)
+
+	// Ku and Pu hold the estimated ultimate gain and period once Run
+	// completes successfully.
+	Ku float64
+	Pu float64
+}
+
+// relay tuning tables, expressed as multiples of Ku and Pu.
+type tuningTable struct {
+	kp, ki, kd float64 // multipliers: Kp = kp*Ku, Ki = ki*Ku/Pu, Kd = kd*Ku*Pu
+}
+
+var tuningTables = map[TuningRule]tuningTable{
+	TuningRuleClassic:       {kp: 0.6, ki: 1.2, kd: 0.075},
+	TuningRuleLessOvershoot: {kp: 0.33, ki: 0.66, kd: 0.11},
+	TuningRuleNoOvershoot:   {kp: 0.2, ki: 0.4, kd: 0.0667},
+}
+
+// Run drives the relay test to completion and returns a PID configured with
+// the gains derived from the resulting limit cycle. It blocks until the
+// oscillation stabilizes for StableCycles consecutive cycles or ctx is
+// cancelled.
+func (a *Autotuner) Run(ctx context.Context) (PID, error) {
+	if a.Measure == nil || a.Output == nil {
+		return PID{}, errors.New("pid: Autotuner requires both Measure and Output callbacks")
+	}
+	if a.Step <= 0 {
+		return PID{}, errors.New("pid: Autotuner.Step must be positive")
+	}
+
+	interval := a.SampleInterval
+	if interval <= 0 {
+		interval = 10 * time.Millisecond
+	}
+	stableCycles := a.StableCycles
+	if stableCycles <= 0 {
+		stableCycles = 3
+	}
+	tolerance := a.Tolerance
+	if tolerance <= 0 {
+		tolerance = 0.05
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	high := true // currently driving InitialOutput+Step
+	a.Output(a.InitialOutput + a.Step)
+
+	var (
+		peak, trough                 float64
+		havePeak, haveTrough         bool
+		lastPeakTime, lastTroughTime time.Time
+		lastAmplitude                float64
+		lastPeriod                   float64
+		agreeing                     int
+	)
+	extreme := a.Measure()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return PID{}, ctx.Err()
+		case now := <-ticker.C:
+			measured := a.Measure()
+
+			// A lagged plant keeps moving in its prior direction for a
+			// while after the relay switches, so the true peak is reached
+			// during the following low phase (not the high phase that
+			// crossed the threshold), and vice versa for the trough. Track
+			// the opposite extreme of whichever phase we're currently in.
+			if high {
+				if measured < extreme {
+					extreme = measured
+				}
+			} else {
+				if measured > extreme {
+					extreme = measured
+				}
+			}
+
+			crossedDown := high && measured > a.Setpoint+a.NoiseBand
+			crossedUp := !high && measured < a.Setpoint-a.NoiseBand
+			if !crossedUp && !crossedDown {
+				continue
+			}
+
+			// Measure the period over a full cycle, from one threshold
+			// crossing to the next crossing in the same direction, rather
+			// than between adjacent crossings: the two half-cycles of a relay limit
+			// cycle need not take equal time, so comparing adjacent
+			// half-periods would never agree even once the oscillation has
+			// fully settled.
+			var period float64
+			havePeriod := false
+			if high {
+				trough = extreme
+				haveTrough = true
+				if !lastTroughTime.IsZero() {
+					period = now.Sub(lastTroughTime).Seconds()
+					havePeriod = true
+				}
+				lastTroughTime = now
+			} else {
+				peak = extreme
+				havePeak = true
+				if !lastPeakTime.IsZero() {
+					period = now.Sub(lastPeakTime).Seconds()
+					havePeriod = true
+				}
+				lastPeakTime = now
+			}
+
+			if havePeriod && havePeak && haveTrough {
+				amplitude := (peak - trough) / 2
+				if lastAmplitude != 0 && lastPeriod != 0 {
+					ampErr := math.Abs(amplitude-lastAmplitude) / lastAmplitude
+					perErr := math.Abs(period-lastPeriod) / lastPeriod
+					if ampErr <= tolerance && perErr <= tolerance {
+						agreeing++
+					} else {
+						agreeing = 0
+					}
+				}
+				lastAmplitude = amplitude
+				lastPeriod = period
+
+				if agreeing >= stableCycles {
+					a.Output(a.InitialOutput)
+					return a.finish(amplitude, period)
+				}
+			}
+
+			high = !high
+			if high {
+				a.Output(a.InitialOutput + a.Step)
+			} else {
+				a.Output(a.InitialOutput - a.Step)
+			}
+			extreme = measured
+		}
+	}
+}
+
+// finish computes Ku/Pu from the stabilized limit cycle and applies the
+// configured tuning table to produce gains.
+func (a *Autotuner) finish(amplitude, period float64) (PID, error) {
+	if amplitude <= 0 {
+		return PID{}, errors.New("pid: Autotuner observed zero oscillation amplitude")
+	}
+
+	a.Ku = 4 * a.Step / (math.Pi * amplitude)
+	a.Pu = period
+
+	table, ok := tuningTables[a.Rule]
+	if !ok {
+		table = tuningTables[TuningRuleClassic]
+	}
+
+	return PID{
+		Kp: table.kp * a.Ku,
+		Ki: table.ki * a.Ku / a.Pu,
+		Kd: table.kd * a.Ku * a.Pu,
+	}, nil
+}
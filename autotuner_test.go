@@ -0,0 +1,102 @@
+package pid
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// simulatedFOPDTPlant is a minimal first-order-plus-dead-time plant used to
+// exercise the Autotuner against a system that reliably limit-cycles under
+// relay feedback.
+type simulatedFOPDTPlant struct {
+	y       float64
+	timeK   float64 // plant time constant
+	delay   []float64
+	delayAt int
+}
+
+func newSimulatedFOPDTPlant(deadSteps int) *simulatedFOPDTPlant {
+	return &simulatedFOPDTPlant{timeK: 0.2, delay: make([]float64, deadSteps)}
+}
+
+func (p *simulatedFOPDTPlant) step(u, dt float64) float64 {
+	delayed := p.delay[p.delayAt]
+	p.delay[p.delayAt] = u
+	p.delayAt = (p.delayAt + 1) % len(p.delay)
+
+	p.y += (delayed - p.y) * dt / p.timeK
+	return p.y
+}
+
+func TestAutotunerRun(t *testing.T) {
+	plant := newSimulatedFOPDTPlant(4)
+	interval := time.Millisecond
+	output := 0.0
+
+	tuner := Autotuner{
+		Setpoint:       5,
+		Step:           10,
+		NoiseBand:      0.2,
+		InitialOutput:  0,
+		SampleInterval: interval,
+		StableCycles:   2,
+		Tolerance:      0.1,
+		Output:         func(u float64) { output = u },
+		Measure:        func() float64 { return plant.step(output, interval.Seconds()) },
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := tuner.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if tuner.Ku <= 0 || tuner.Pu <= 0 {
+		t.Fatalf("expected positive Ku/Pu, got Ku=%v Pu=%v", tuner.Ku, tuner.Pu)
+	}
+	if result.Kp <= 0 || result.Ki <= 0 || result.Kd <= 0 {
+		t.Errorf("expected positive gains, got %+v", result)
+	}
+
+	// This plant's true limit cycle (measured directly from its steady-state
+	// oscillation, independent of Autotuner) has amplitude ~0.42 and period
+	// ~0.043s; a biased peak/trough tracker systematically collapses the
+	// amplitude toward NoiseBand (0.2) and overestimates Ku.
+	const wantAmplitude = 0.42
+	gotAmplitude := 4 * tuner.Step / (math.Pi * tuner.Ku)
+	if math.Abs(gotAmplitude-wantAmplitude)/wantAmplitude > 0.25 {
+		t.Errorf("expected amplitude close to %v, got %v (Ku=%v)", wantAmplitude, gotAmplitude, tuner.Ku)
+	}
+	const wantPeriod = 0.043
+	if math.Abs(tuner.Pu-wantPeriod)/wantPeriod > 0.25 {
+		t.Errorf("expected Pu close to %v, got %v", wantPeriod, tuner.Pu)
+	}
+}
+
+func TestAutotunerRequiresCallbacks(t *testing.T) {
+	tuner := Autotuner{Setpoint: 5, Step: 1}
+	if _, err := tuner.Run(context.Background()); err == nil {
+		t.Error("expected error when Measure/Output are not set")
+	}
+}
+
+func TestAutotunerRejectsNonPositiveStep(t *testing.T) {
+	tuner := Autotuner{
+		Setpoint: 5,
+		Step:     0,
+		Measure:  func() float64 { return 0 },
+		Output:   func(float64) {},
+	}
+	if _, err := tuner.Run(context.Background()); err == nil {
+		t.Error("expected error for non-positive Step")
+	}
+}
+
+func TestAutotunerTuningTables(t *testing.T) {
+	if math.Abs(tuningTables[TuningRuleClassic].kp-0.6) > 1e-9 {
+		t.Errorf("unexpected classic Kp multiplier: %v", tuningTables[TuningRuleClassic].kp)
+	}
+}
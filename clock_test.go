@@ -0,0 +1,54 @@
+package pid
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSimulatedClockAdvance(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(0, 0))
+	if !clock.Now().Equal(time.Unix(0, 0)) {
+		t.Fatalf("expected clock to start at the given time, got %v", clock.Now())
+	}
+
+	clock.Advance(5 * time.Second)
+	want := time.Unix(5, 0)
+	if !clock.Now().Equal(want) {
+		t.Errorf("expected clock to read %v after advancing, got %v", want, clock.Now())
+	}
+}
+
+func TestUpdateWithDtMatchesUpdate(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(0, 0))
+	viaClock := PID{Kp: 1.0, Ki: 0.1, Kd: 0.05, MinOutput: -100, MaxOutput: 100, Clock: clock}
+	viaDt := PID{Kp: 1.0, Ki: 0.1, Kd: 0.05, MinOutput: -100, MaxOutput: 100}
+
+	// Seed both controllers identically: the first call to Update always
+	// no-ops (it only seeds lastTime), so it has no dt-based equivalent.
+	clock.Advance(10 * time.Millisecond)
+	viaClock.Update(10, 5)
+
+	for i := 0; i < 10; i++ {
+		clock.Advance(10 * time.Millisecond)
+		wantOutput := viaClock.Update(10, 5)
+		gotOutput := viaDt.UpdateWithDt(10, 5, 0.01)
+
+		if math.Abs(gotOutput-wantOutput) > 1e-9 {
+			t.Fatalf("step %d: UpdateWithDt diverged from clock-driven Update: got %v, want %v", i, gotOutput, wantOutput)
+		}
+	}
+}
+
+func TestUpdateWithDtRejectsNonPositiveDt(t *testing.T) {
+	pid := PID{Kp: 1.0, MinOutput: -100, MaxOutput: 100}
+	pid.UpdateWithDt(10, 5, 0.01)
+	want := pid.lastOutput
+
+	if output := pid.UpdateWithDt(10, 5, 0); output != want {
+		t.Errorf("expected zero dt to return lastOutput (%v), but got %v", want, output)
+	}
+	if output := pid.UpdateWithDt(10, 5, -1); output != want {
+		t.Errorf("expected negative dt to return lastOutput (%v), but got %v", want, output)
+	}
+}
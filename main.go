@@ -5,6 +5,58 @@ import (
 	"time"
 )
 
+// defaultDerivativeTau is the low-pass filter time constant used for the D
+// term when DerivativeTau is left unset.
+const defaultDerivativeTau = 7.96e-3
+
+// Clock abstracts time retrieval so PID.Update can be driven
+// deterministically in simulations and tests without relying on the wall
+// clock or time.Sleep.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock, backed by time.Now.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// SimulatedClock is a Clock whose time only advances when Advance is
+// called, for fixed-step simulations and deterministic replay.
+type SimulatedClock struct {
+	now time.Time
+}
+
+// NewSimulatedClock returns a SimulatedClock starting at t.
+func NewSimulatedClock(t time.Time) *SimulatedClock {
+	return &SimulatedClock{now: t}
+}
+
+// Now returns the simulated clock's current time.
+func (c *SimulatedClock) Now() time.Time { return c.now }
+
+// Advance moves the simulated clock forward by d.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// AntiWindupMode selects how PID counters integrator windup while the
+// output is saturated.
+type AntiWindupMode int
+
+const (
+	// AntiWindupNone disables anti-windup protection entirely.
+	AntiWindupNone AntiWindupMode = iota
+	// AntiWindupClamp freezes the integrator while the output is
+	// saturated. This is the legacy behavior also enabled by the
+	// AntiWindup bool field.
+	AntiWindupClamp
+	// AntiWindupBackCalculation feeds the difference between the
+	// saturated and unsaturated output back into the integrator, scaled
+	// by TrackingTimeConstant, for smoother recovery from saturation.
+	AntiWindupBackCalculation
+)
+
 // PID represents a Proportional-Integral-Derivative controller.
 type PID struct {
 	// parameters
@@ -15,16 +67,90 @@ type PID struct {
 	MaxOutput  float64 // Maximum output value
 	Deadband   float64 // Deadband to ignore small errors
 	Saturated  bool    // Indicates if the output is saturated
-	AntiWindup bool    // Enable/disable anti-windup protection
+	AntiWindup bool    // Enable/disable clamp-style anti-windup protection
+
+	// AntiWindupMode selects the anti-windup strategy. When left at the
+	// zero value (AntiWindupNone), the legacy AntiWindup bool above is
+	// honored for backward compatibility (true behaves like
+	// AntiWindupClamp). Set this explicitly to opt into
+	// AntiWindupBackCalculation.
+	AntiWindupMode AntiWindupMode
+	// TrackingTimeConstant (Tt) scales how aggressively
+	// AntiWindupBackCalculation unwinds the integrator while saturated.
+	// Defaults to sqrt(Ki*Kd)/Ki when zero and Ki, Kd are both non-zero.
+	TrackingTimeConstant float64
+
+	// PLimit, ILimit, and DLimit symmetrically clamp their respective term
+	// to [-limit, limit] before the terms are summed into the output. A
+	// zero value disables clamping for that term.
+	PLimit float64
+	ILimit float64
+	DLimit float64
+
+	// IntegralMin and IntegralMax bound the accumulated integral state
+	// itself, independent of the output saturation limits above. They are
+	// only applied when IntegralMax > IntegralMin.
+	IntegralMin float64
+	IntegralMax float64
+
+	// Beta and Gamma implement a two-degree-of-freedom PID: Beta weights
+	// the setpoint in the P term (Beta*setpoint - measured) and Gamma
+	// weights it in the D term (Gamma*setpoint - measured), so reference
+	// tracking and disturbance rejection can be tuned independently. Both
+	// default to 1 (the classic, unweighted PID) when left unset.
+	Beta  float64
+	Gamma float64
+
+	// DerivativeOnMeasurement computes the D term from the derivative of
+	// the measurement rather than the derivative of the error, which
+	// eliminates "derivative kick" on setpoint changes. Gamma is ignored
+	// while this is enabled, since the derivative no longer sees the
+	// setpoint at all.
+	DerivativeOnMeasurement bool
+
+	// DerivativeTau is the time constant of the first-order low-pass
+	// filter applied to the D term. Defaults to defaultDerivativeTau when
+	// zero.
+	DerivativeTau float64
+
+	// MaxDt clamps the effective sample period used by Update, guarding
+	// against dt explosions after a long pause (e.g. a debugger breakpoint
+	// or a stalled goroutine). A zero value disables clamping.
+	MaxDt float64
+
+	// Fault is set when Update rejects a non-finite (NaN or +/-Inf)
+	// setpoint or measurement instead of letting it propagate into the
+	// controller's state.
+	Fault bool
+
+	// Clock supplies the current time to Update. Defaults to the real
+	// wall clock when nil; set it to a SimulatedClock for deterministic
+	// tests and fixed-step simulations, or use UpdateWithDt to bypass
+	// timing entirely.
+	Clock Clock
 
 	// internal state
-	prevError  float64   // Previous error for derivative calculation
-	integral   float64   // Integral sum
-	lastTime   time.Time // Last update time
-	lastOutput float64   // Last output value
+	prevError    float64   // Previous error for derivative calculation
+	prevMeasured float64   // Previous measurement, for derivative-on-measurement
+	prevDInput   float64   // Previous setpoint-weighted D input, for derivative-on-error
+	dFiltered    float64   // Low-pass filtered D term
+	integral     float64   // Integral sum
+	lastTime     time.Time // Last update time
+	lastOutput   float64   // Last output value
 }
 
-// Update calculates and returns the control output based on the current reference value.
+// clock returns the Clock used to time Update calls, defaulting to the
+// real wall clock when Clock is unset.
+func (pid *PID) clock() Clock {
+	if pid.Clock == nil {
+		return realClock{}
+	}
+	return pid.Clock
+}
+
+// Update calculates and returns the control output based on the current
+// reference value, timing the sample period off of pid.Clock (the real
+// wall clock by default).
 //
 // Parameters:
 //   - setpoint: The desired setpoint
@@ -33,40 +159,108 @@ type PID struct {
 // Returns:
 //   - float64: The calculated control output
 func (pid *PID) Update(setpoint, measured float64) float64 {
-	now := time.Now()
+	now := pid.clock().Now()
 	if pid.lastTime.IsZero() {
 		pid.lastTime = now
+		return pid.lastOutput
+	}
+
+	dt := now.Sub(pid.lastTime).Seconds()
+	if dt <= 0 {
+		return pid.lastOutput
 	}
+	pid.lastTime = now
+
+	return pid.UpdateWithDt(setpoint, measured, dt)
+}
+
+// UpdateWithDt calculates and returns the control output using an
+// explicitly supplied sample period, bypassing pid.Clock entirely. This
+// lets the controller be driven in fixed-step simulations, replay recorded
+// data at arbitrary speed, and be tested deterministically without
+// time.Sleep.
+//
+// Parameters:
+//   - setpoint: The desired setpoint
+//   - measured: The current measured value of the process variable
+//   - dt: The sample period, in seconds
+//
+// Returns:
+//   - float64: The calculated control output
+func (pid *PID) UpdateWithDt(setpoint, measured, dt float64) float64 {
+	if !isFinite(setpoint) || !isFinite(measured) {
+		pid.Fault = true
+		return pid.lastOutput
+	}
+	pid.Fault = false
 
-	dt := time.Since(pid.lastTime).Seconds()
 	if dt <= 0 {
 		return pid.lastOutput
 	}
+	if pid.MaxDt > 0 && dt > pid.MaxDt {
+		dt = pid.MaxDt
+	}
 
-	error := setpoint - measured
+	error := clamp(setpoint-measured, -math.MaxFloat64, math.MaxFloat64)
 
 	// Apply deadband
 	if math.Abs(error) < pid.Deadband {
 		return pid.lastOutput
 	}
 
-	// calculate P term
-	pTerm := pid.Kp * error
+	// Setpoint weighting: Beta and Gamma default to 1 (full error),
+	// matching the classic, unweighted PID when left unset.
+	beta := pid.Beta
+	if beta == 0 {
+		beta = 1
+	}
+	gamma := pid.Gamma
+	if gamma == 0 {
+		gamma = 1
+	}
+
+	// calculate P term using the setpoint-weighted input
+	pTerm := pid.Kp * (beta*setpoint - measured)
+	if pid.PLimit != 0 {
+		pTerm = clampSymmetric(pTerm, pid.PLimit)
+	}
 
 	// Calculate D term (only if Kd is non-zero)
 	dTerm := 0.0
 	if pid.Kd != 0 {
-		dTerm = pid.Kd * (error - pid.prevError) / dt
+		var dRaw float64
+		if pid.DerivativeOnMeasurement {
+			dRaw = -(measured - pid.prevMeasured) / dt
+		} else {
+			dInput := gamma*setpoint - measured
+			dRaw = (dInput - pid.prevDInput) / dt
+		}
+		dRaw = clamp(dRaw, -math.MaxFloat64, math.MaxFloat64)
+
+		tau := pid.DerivativeTau
+		if tau == 0 {
+			tau = defaultDerivativeTau
+		}
+		pid.dFiltered += (dRaw - pid.dFiltered) * dt / (tau + dt)
+
+		dTerm = pid.Kd * pid.dFiltered
+		if pid.DLimit != 0 {
+			dTerm = clampSymmetric(dTerm, pid.DLimit)
+		}
 	}
 
 	// Calculate I term (only if Ki is non-zero)
 	iTerm := 0.0
-	integral := 0.5 * dt * (error + pid.prevError)
+	integral := clamp(0.5*dt*(error+pid.prevError), -math.MaxFloat64, math.MaxFloat64)
 	if pid.Ki != 0 {
 		iTerm = pid.Ki * pid.integral
+		if pid.ILimit != 0 {
+			iTerm = clampSymmetric(iTerm, pid.ILimit)
+		}
 	}
 
-	output := pTerm + iTerm + dTerm
+	unsaturated := pTerm + iTerm + dTerm
+	output := unsaturated
 
 	// Apply output limits
 	saturated := false
@@ -78,22 +272,85 @@ func (pid *PID) Update(setpoint, measured float64) float64 {
 		saturated = error < 0
 	}
 
-	// update previous error, time, and last output for use in next iteration
+	// update previous error and last output for use in next iteration
 	pid.prevError = error
-	pid.lastTime = now
+	pid.prevMeasured = measured
+	pid.prevDInput = gamma*setpoint - measured
 	pid.lastOutput = output
 	pid.Saturated = saturated
-	if pid.AntiWindup && !pid.Saturated {
-		pid.integral = integral
+
+	mode := pid.AntiWindupMode
+	if mode == AntiWindupNone && pid.AntiWindup {
+		mode = AntiWindupClamp
+	}
+
+	switch mode {
+	case AntiWindupNone:
+		pid.integral += integral
+		if pid.IntegralMax > pid.IntegralMin {
+			pid.integral = clamp(pid.integral, pid.IntegralMin, pid.IntegralMax)
+		}
+	case AntiWindupClamp:
+		if !pid.Saturated {
+			pid.integral += integral
+			if pid.IntegralMax > pid.IntegralMin {
+				pid.integral = clamp(pid.integral, pid.IntegralMin, pid.IntegralMax)
+			}
+		}
+	case AntiWindupBackCalculation:
+		tt := pid.TrackingTimeConstant
+		if tt == 0 && pid.Ki != 0 && pid.Kd != 0 {
+			tt = math.Sqrt(pid.Kd / pid.Ki)
+		}
+		// pid.integral holds bare trapezoidal ∫error dt, same as the other
+		// modes (iTerm = Ki*pid.integral); the back-calculation correction
+		// is expressed in output units, so it must be divided by Ki before
+		// folding into that same state, or iTerm ends up scaled by Ki².
+		switch {
+		case tt != 0 && pid.Ki != 0:
+			pid.integral += integral + (1/(pid.Ki*tt))*(output-unsaturated)*dt
+		case !pid.Saturated:
+			// No tracking time constant could be derived (TrackingTimeConstant
+			// unset and Kd == 0): fall back to clamp-style freezing instead
+			// of accumulating with no anti-windup protection at all.
+			pid.integral += integral
+		}
+		if pid.IntegralMax > pid.IntegralMin {
+			pid.integral = clamp(pid.integral, pid.IntegralMin, pid.IntegralMax)
+		}
 	}
 
 	return output
 }
 
+// clamp restricts v to the inclusive range [min, max].
+func clamp(v, min, max float64) float64 {
+	if v > max {
+		return max
+	}
+	if v < min {
+		return min
+	}
+	return v
+}
+
+// clampSymmetric restricts v to [-limit, limit]. limit is assumed positive.
+func clampSymmetric(v, limit float64) float64 {
+	return clamp(v, -limit, limit)
+}
+
+// isFinite reports whether v is neither NaN nor +/-Inf.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
 // Reset resets the PID controller's internal state.
 // This includes resetting the previous error, integral sum, and last update time.
 func (pid *PID) Reset() {
 	pid.prevError = 0
+	pid.prevMeasured = 0
+	pid.prevDInput = 0
+	pid.dFiltered = 0
 	pid.integral = 0
-	pid.lastTime = time.Now()
+	pid.lastTime = pid.clock().Now()
 }
@@ -8,11 +8,12 @@ import (
 
 func TestPIDUpdate(t *testing.T) {
 	tests := []struct {
-		name     string
-		pid      PID
-		setpoint float64
-		measured float64
-		expected float64
+		name      string
+		pid       PID
+		setpoint  float64
+		measured  float64
+		expected  float64
+		tolerance float64 // defaults to 0.1 when zero
 	}{
 		{
 			name: "Basic P controller",
@@ -42,7 +43,12 @@ func TestPIDUpdate(t *testing.T) {
 			},
 			setpoint: 10,
 			measured: 5,
-			expected: 5.05, // Approximate, as it depends on time
+			// Now that the integral actually accumulates (see PID.Update in
+			// main.go), iTerm approaches Ki*error*t ~= 0.1*5*1 = 0.5 on top
+			// of the P term over the ~1s of simulated time this loop runs,
+			// with the D term decaying back to ~0 (about 5.49 in all).
+			expected:  5.5,
+			tolerance: 1.0,
 		},
 		{
 			name: "Controller with deadband",
@@ -59,12 +65,19 @@ func TestPIDUpdate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			clock := NewSimulatedClock(time.Unix(0, 0))
+			tt.pid.Clock = clock
+
 			output := 0.0
 			for i := 0; i < 100; i++ {
+				clock.Advance(10 * time.Millisecond)
 				output = tt.pid.Update(tt.setpoint, tt.measured)
-				time.Sleep(10 * time.Millisecond)
 			}
-			if math.Abs(output-tt.expected) > 0.1 {
+			tolerance := tt.tolerance
+			if tolerance == 0 {
+				tolerance = 0.1
+			}
+			if math.Abs(output-tt.expected) > tolerance {
 				t.Errorf("Expected output close to %v, but got %v", tt.expected, output)
 			}
 		})
@@ -72,15 +85,17 @@ func TestPIDUpdate(t *testing.T) {
 }
 
 func TestPIDReset(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(0, 0))
 	pid := PID{
 		Kp: 1.0, Ki: 0.1, Kd: 0.05,
 		MinOutput: -100, MaxOutput: 100,
+		Clock: clock,
 	}
 
 	// Update the PID to set some internal state
 	for i := 0; i < 20; i++ {
+		clock.Advance(10 * time.Millisecond)
 		pid.Update(10, 5)
-		time.Sleep(10 * time.Millisecond)
 	}
 
 	// Reset the PID
@@ -94,8 +109,8 @@ func TestPIDReset(t *testing.T) {
 		t.Errorf("Expected integral to be 0 after reset, but got %v", pid.integral)
 	}
 
-	if time.Since(pid.lastTime) > time.Second {
-		t.Errorf("Expected lastTime to be recent after reset, but got %v", pid.lastTime)
+	if pid.lastTime != clock.Now() {
+		t.Errorf("Expected lastTime to match the clock after reset, but got %v", pid.lastTime)
 	}
 }
 
@@ -117,16 +132,263 @@ func TestPIDAntiWindup(t *testing.T) {
 	}
 }
 
+func TestPIDTermLimits(t *testing.T) {
+	pid := PID{
+		Kp: 10.0, Ki: 0, Kd: 0,
+		MinOutput: -100, MaxOutput: 100,
+		PLimit: 5,
+	}
+
+	output := pid.UpdateWithDt(10, 0, 0.01) // unclamped P term would be 100
+	if output != 5 {
+		t.Errorf("expected P term clamped to PLimit (5), but got %v", output)
+	}
+}
+
+func TestPIDIntegralClamping(t *testing.T) {
+	pid := PID{
+		Kp: 0, Ki: 1.0, Kd: 0,
+		MinOutput: -1000, MaxOutput: 1000,
+		AntiWindup:  true,
+		IntegralMin: -2,
+		IntegralMax: 2,
+	}
+
+	for i := 0; i < 50; i++ {
+		pid.Update(100, 0)
+		time.Sleep(time.Millisecond)
+	}
+
+	if pid.integral > pid.IntegralMax || pid.integral < pid.IntegralMin {
+		t.Errorf("expected integral within [%v, %v], but got %v", pid.IntegralMin, pid.IntegralMax, pid.integral)
+	}
+}
+
+func TestPIDSetpointWeighting(t *testing.T) {
+	pid := PID{
+		Kp: 1.0, Ki: 0, Kd: 0,
+		MinOutput: -100, MaxOutput: 100,
+		Beta: 0.5,
+	}
+
+	// P term should use Beta*setpoint - measured, not the full error.
+	output := pid.UpdateWithDt(10, 0, 0.01)
+	want := 0.5*10 - 0
+	if math.Abs(output-want) > 0.01 {
+		t.Errorf("expected weighted P output close to %v, but got %v", want, output)
+	}
+}
+
+func TestPIDDerivativeOnMeasurement(t *testing.T) {
+	pid := PID{
+		Kp: 0, Ki: 0, Kd: 1.0,
+		MinOutput: -1000, MaxOutput: 1000,
+		DerivativeOnMeasurement: true,
+		DerivativeTau:           0, // use the default filter
+	}
+
+	pid.Update(10, 0)
+	time.Sleep(5 * time.Millisecond)
+	// Setpoint jumps but measurement doesn't move: derivative-on-error
+	// would "kick" here, derivative-on-measurement should not.
+	output := pid.Update(100, 0)
+	if math.Abs(output) > 1.0 {
+		t.Errorf("expected no derivative kick on setpoint change, but got %v", output)
+	}
+}
+
+func TestPIDBackCalculationAntiWindup(t *testing.T) {
+	pid := PID{
+		Kp: 1.0, Ki: 0.5, Kd: 0,
+		MinOutput: -10, MaxOutput: 10,
+		AntiWindupMode:       AntiWindupBackCalculation,
+		TrackingTimeConstant: 0.1,
+	}
+
+	// Drive the controller hard into saturation; back-calculation should
+	// push the integrator away from zero to counteract the saturation.
+	for i := 0; i < 50; i++ {
+		pid.Update(100, 0)
+		time.Sleep(time.Millisecond)
+	}
+	windUp := pid.integral
+	if windUp == 0 {
+		t.Fatal("expected back-calculation to move the integrator while saturated")
+	}
+
+	// Reverse the error; back-calculation should now correct the
+	// integrator back toward zero instead of staying pinned, unlike a
+	// frozen (clamp-style) integrator while saturated the other way.
+	for i := 0; i < 10; i++ {
+		pid.Update(-100, 0)
+		time.Sleep(time.Millisecond)
+	}
+
+	if math.Abs(pid.integral) >= math.Abs(windUp) {
+		t.Errorf("expected integral magnitude to shrink after reversing error, went from %v to %v", windUp, pid.integral)
+	}
+}
+
+func TestPIDBackCalculationMatchesClampWhenUnsaturated(t *testing.T) {
+	// While unsaturated, back-calculation's correction term is zero, so its
+	// integrator must accumulate bare error like the other modes (iTerm =
+	// Ki*integral) rather than folding Ki into the integral state a second
+	// time.
+	clamp := PID{
+		Kp: 0, Ki: 2.0, Kd: 0,
+		MinOutput: -1000, MaxOutput: 1000,
+		AntiWindupMode: AntiWindupClamp,
+	}
+	backCalc := PID{
+		Kp: 0, Ki: 2.0, Kd: 0,
+		MinOutput:            -1000,
+		MaxOutput:            1000,
+		AntiWindupMode:       AntiWindupBackCalculation,
+		TrackingTimeConstant: 0.1,
+	}
+
+	// The I term uses the integral accumulated before the current step, so
+	// take one step first for the second output to reflect it.
+	clamp.UpdateWithDt(1, 0, 0.01)
+	backCalc.UpdateWithDt(1, 0, 0.01)
+	wantOutput := clamp.UpdateWithDt(1, 0, 0.01)
+	gotOutput := backCalc.UpdateWithDt(1, 0, 0.01)
+	if math.Abs(gotOutput-wantOutput) > 1e-9 {
+		t.Errorf("expected back-calculation output to match clamp while unsaturated, got %v, want %v", gotOutput, wantOutput)
+	}
+}
+
+func TestPIDBackCalculationFallsBackToClampWithoutTrackingTimeConstant(t *testing.T) {
+	// With Kd == 0 and TrackingTimeConstant unset, no tracking time constant
+	// can be derived; back-calculation should then freeze the integrator
+	// while saturated, like AntiWindupClamp, rather than accumulate with no
+	// anti-windup protection at all.
+	clamp := PID{
+		Kp: 0, Ki: 2.0, Kd: 0,
+		MinOutput: -10, MaxOutput: 10,
+		AntiWindupMode: AntiWindupClamp,
+	}
+	backCalc := PID{
+		Kp: 0, Ki: 2.0, Kd: 0,
+		MinOutput:      -10,
+		MaxOutput:      10,
+		AntiWindupMode: AntiWindupBackCalculation,
+	}
+
+	for i := 0; i < 50; i++ {
+		clamp.UpdateWithDt(100, 0, 0.01)
+		backCalc.UpdateWithDt(100, 0, 0.01)
+	}
+
+	if math.Abs(backCalc.integral-clamp.integral) > 1e-9 {
+		t.Errorf("expected back-calculation integral to match clamp's when no tracking time constant is available, got %v, want %v", backCalc.integral, clamp.integral)
+	}
+}
+
+func TestPIDRejectsNaN(t *testing.T) {
+	pid := PID{
+		Kp: 1.0, Ki: 0, Kd: 0,
+		MinOutput: -100, MaxOutput: 100,
+	}
+
+	pid.Update(10, 5) // establish a known lastOutput
+	want := pid.lastOutput
+
+	output := pid.Update(math.NaN(), 5)
+	if output != want {
+		t.Errorf("expected NaN setpoint to return lastOutput (%v), but got %v", want, output)
+	}
+	if !pid.Fault {
+		t.Error("expected Fault to be set after a NaN setpoint")
+	}
+
+	output = pid.Update(10, math.NaN())
+	if output != want {
+		t.Errorf("expected NaN measured to return lastOutput (%v), but got %v", want, output)
+	}
+	if !pid.Fault {
+		t.Error("expected Fault to be set after a NaN measurement")
+	}
+
+	// A subsequent valid update should clear the fault.
+	pid.Update(10, 5)
+	if pid.Fault {
+		t.Error("expected Fault to clear after a valid update")
+	}
+}
+
+func TestPIDRejectsInf(t *testing.T) {
+	pid := PID{
+		Kp: 1.0, Ki: 0, Kd: 0,
+		MinOutput: -100, MaxOutput: 100,
+	}
+
+	pid.Update(10, 5) // establish a known lastOutput
+	want := pid.lastOutput
+
+	output := pid.Update(math.Inf(1), 5)
+	if output != want {
+		t.Errorf("expected Inf setpoint to return lastOutput (%v), but got %v", want, output)
+	}
+	if !pid.Fault {
+		t.Error("expected Fault to be set after an Inf setpoint")
+	}
+
+	output = pid.Update(10, math.Inf(-1))
+	if output != want {
+		t.Errorf("expected -Inf measured to return lastOutput (%v), but got %v", want, output)
+	}
+	if !pid.Fault {
+		t.Error("expected Fault to be set after a -Inf measurement")
+	}
+
+	// A rejected Inf must not leak into prevMeasured/prevDInput and corrupt
+	// the next derivative computation.
+	if math.IsInf(pid.prevMeasured, 0) {
+		t.Errorf("expected prevMeasured to stay finite, but got %v", pid.prevMeasured)
+	}
+
+	// A subsequent valid update should clear the fault.
+	pid.Update(10, 5)
+	if pid.Fault {
+		t.Error("expected Fault to clear after a valid update")
+	}
+}
+
+func TestPIDMaxDt(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(0, 0))
+	pid := PID{
+		Kp: 0, Ki: 1.0, Kd: 0,
+		MinOutput: -1000, MaxOutput: 1000,
+		AntiWindup: true,
+		MaxDt:      0.01,
+		Clock:      clock,
+	}
+
+	clock.Advance(time.Millisecond)
+	pid.Update(10, 0)
+	clock.Advance(200 * time.Millisecond) // far longer than MaxDt
+	pid.Update(10, 0)
+
+	// With dt clamped to MaxDt, the integral step can be at most
+	// Ki * error * MaxDt; an unclamped 200ms pause would blow well past it.
+	if math.Abs(pid.integral) > 10*pid.MaxDt*1.5 {
+		t.Errorf("expected integral step bounded by MaxDt, but got %v", pid.integral)
+	}
+}
+
 func TestPIDSaturation(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(0, 0))
 	pid := PID{
 		Kp: 10.0, Ki: 0, Kd: 0,
 		MinOutput: -20, MaxOutput: 20,
+		Clock: clock,
 	}
 
 	output := 0.0
 	for i := 0; i < 100; i++ {
+		clock.Advance(10 * time.Millisecond)
 		output = pid.Update(10, 0)
-		time.Sleep(10 * time.Millisecond)
 	}
 
 	if output != pid.MaxOutput {
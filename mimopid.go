@@ -0,0 +1,70 @@
+package pid
+
+// MIMOPID runs N independent PID loops in a single Update call. Each
+// channel keeps its own gains, limits, deadband, and anti-windup setting,
+// while an optional coupling matrix lets a channel's control effort be
+// influenced by other channels' errors (useful for coupled axes such as
+// differential-drive or multi-motor thermal control).
+type MIMOPID struct {
+	Channels []PID
+
+	// Coupling[i][j] scales channel j's error into channel i's effort.
+	// Diagonal entries are ignored: a channel's own error always enters
+	// with gain 1. A nil Coupling disables cross-coupling entirely.
+	Coupling [][]float64
+}
+
+// Update steps every channel's PID loop once and returns the per-channel
+// control outputs. setpoints and measured must each have one entry per
+// channel; mismatched lengths return a zero-valued output slice.
+func (m *MIMOPID) Update(setpoints, measured []float64) []float64 {
+	n := len(m.Channels)
+	outputs := make([]float64, n)
+	if len(setpoints) != n || len(measured) != n {
+		return outputs
+	}
+
+	errs := make([]float64, n)
+	for i := range m.Channels {
+		errs[i] = setpoints[i] - measured[i]
+	}
+
+	for i := range m.Channels {
+		coupledErr := errs[i]
+		if i < len(m.Coupling) {
+			for j, gain := range m.Coupling[i] {
+				if j == i || j >= n || gain == 0 {
+					continue
+				}
+				coupledErr += gain * errs[j]
+			}
+		}
+		// Feed the coupled error through as if it were the measurement,
+		// so the channel's own PID.Update computes setpoint-measured ==
+		// coupledErr without duplicating its internal math.
+		outputs[i] = m.Channels[i].Update(setpoints[i], setpoints[i]-coupledErr)
+	}
+
+	return outputs
+}
+
+// Reset clears the internal state of a single channel.
+func (m *MIMOPID) Reset(channel int) {
+	m.Channels[channel].Reset()
+}
+
+// ResetAll clears the internal state of every channel.
+func (m *MIMOPID) ResetAll() {
+	for i := range m.Channels {
+		m.Channels[i].Reset()
+	}
+}
+
+// SaturatedFlags reports, per channel, whether its last Update saturated.
+func (m *MIMOPID) SaturatedFlags() []bool {
+	flags := make([]bool, len(m.Channels))
+	for i, c := range m.Channels {
+		flags[i] = c.Saturated
+	}
+	return flags
+}
@@ -0,0 +1,91 @@
+package pid
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMIMOPIDUpdateIndependentChannels(t *testing.T) {
+	m := MIMOPID{
+		Channels: []PID{
+			{Kp: 1.0, MinOutput: -100, MaxOutput: 100},
+			{Kp: 2.0, MinOutput: -100, MaxOutput: 100},
+		},
+	}
+
+	m.Update([]float64{10, 10}, []float64{5, 5}) // seed: a channel's first Update only establishes timing
+	outputs := m.Update([]float64{10, 10}, []float64{5, 5})
+	if math.Abs(outputs[0]-5) > 0.01 {
+		t.Errorf("channel 0: expected output close to 5, got %v", outputs[0])
+	}
+	if math.Abs(outputs[1]-10) > 0.01 {
+		t.Errorf("channel 1: expected output close to 10, got %v", outputs[1])
+	}
+}
+
+func TestMIMOPIDCoupling(t *testing.T) {
+	m := MIMOPID{
+		Channels: []PID{
+			{Kp: 1.0, MinOutput: -100, MaxOutput: 100},
+			{Kp: 1.0, MinOutput: -100, MaxOutput: 100},
+		},
+		Coupling: [][]float64{
+			{0, 0.5},
+			{0, 0},
+		},
+	}
+
+	// Channel 0's error should be boosted by half of channel 1's error.
+	m.Update([]float64{10, 10}, []float64{5, 0}) // seed: a channel's first Update only establishes timing
+	outputs := m.Update([]float64{10, 10}, []float64{5, 0})
+	want := 5 + 0.5*10
+	if math.Abs(outputs[0]-want) > 0.01 {
+		t.Errorf("expected coupled output close to %v, got %v", want, outputs[0])
+	}
+}
+
+func TestMIMOPIDResetAndResetAll(t *testing.T) {
+	m := MIMOPID{
+		Channels: []PID{
+			{Kp: 1.0, Ki: 0.1, MinOutput: -100, MaxOutput: 100, AntiWindup: true},
+			{Kp: 1.0, Ki: 0.1, MinOutput: -100, MaxOutput: 100, AntiWindup: true},
+		},
+	}
+
+	for i := 0; i < 10; i++ {
+		m.Update([]float64{10, 10}, []float64{5, 5})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	m.Reset(0)
+	if m.Channels[0].integral != 0 {
+		t.Errorf("expected channel 0 integral reset, got %v", m.Channels[0].integral)
+	}
+
+	m.ResetAll()
+	for i, c := range m.Channels {
+		if c.integral != 0 {
+			t.Errorf("expected channel %d integral reset, got %v", i, c.integral)
+		}
+	}
+}
+
+func TestMIMOPIDSaturatedFlags(t *testing.T) {
+	m := MIMOPID{
+		Channels: []PID{
+			{Kp: 10.0, MinOutput: -20, MaxOutput: 20},
+			{Kp: 1.0, MinOutput: -20, MaxOutput: 20},
+		},
+	}
+
+	m.Update([]float64{10, 10}, []float64{0, 5}) // seed: a channel's first Update only establishes timing
+	m.Update([]float64{10, 10}, []float64{0, 5})
+	flags := m.SaturatedFlags()
+	if !flags[0] {
+		t.Error("expected channel 0 to be saturated")
+	}
+	if flags[1] {
+		t.Error("expected channel 1 not to be saturated")
+	}
+}